@@ -0,0 +1,191 @@
+package nimbusbridge
+
+/*
+#include <stdlib.h>
+#include <stdbool.h>
+
+// Passing "-lnimbus" to the Go linker through "-extldflags" is not enough. We need it in here, for some reason.
+#cgo LDFLAGS: -Wl,-rpath,'$ORIGIN' -L${SRCDIR}/../../build -lnimbus
+#include "libnimbus.h"
+
+void receiveHandler_cgo(received_message * msg, void* udata); // Forward declaration.
+*/
+import "C"
+
+import (
+	"context"
+	"runtime"
+	"time"
+	"unsafe"
+
+	gopointer "github.com/mattn/go-pointer"
+)
+
+// Options configures a Bridge at Start time.
+type Options struct {
+	Port       int
+	UseUDP     bool
+	LightNode  bool
+	MinPow     float64
+	PrivateKey []byte // 32 bytes, or nil to let Nim generate one.
+	VerifyNode bool
+
+	// QueueSize bounds how many RoutineQueue submissions may be pending at
+	// once before Submit blocks the calling goroutine.
+	QueueSize int
+	// PollInterval is how long the Nim thread sleeps between nimbus_poll
+	// calls. It mirrors the sleeps in the existing example programs.
+	PollInterval time.Duration
+}
+
+// Bridge owns the OS thread Nim runs on and drives its poll loop. All calls
+// into the C API happen on that thread; everything else talks to Bridge
+// through Whisper, which is backed by a RoutineQueue.
+type Bridge struct {
+	queue       *RoutineQueue
+	filters     map[string]*Filter
+	mailservers map[string]*mailserverRequest
+	metrics     *metrics
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a Bridge. Call Start to actually boot Nim and begin polling.
+func New() *Bridge {
+	return &Bridge{
+		filters:     make(map[string]*Filter),
+		mailservers: make(map[string]*mailserverRequest),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Start boots Nim and begins the poll loop on a dedicated, locked OS thread.
+// It blocks until nimbus_start has returned, and returns a Whisper handle
+// that is safe to use from any goroutine.
+func (b *Bridge) Start(opts Options) (Whisper, error) {
+	if b.queue != nil {
+		return nil, ErrAlreadyStarted
+	}
+
+	queueSize := opts.QueueSize
+	if queueSize == 0 {
+		queueSize = 64
+	}
+	b.queue = NewRoutineQueue(queueSize)
+
+	pollInterval := opts.PollInterval
+	if pollInterval == 0 {
+		pollInterval = time.Microsecond
+	}
+
+	started := make(chan error, 1)
+	go b.run(opts, pollInterval, started)
+	if err := <-started; err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Stop signals the poll loop to exit and waits for it to do so.
+func (b *Bridge) Stop() {
+	close(b.stop)
+	<-b.done
+}
+
+func (b *Bridge) run(opts Options, pollInterval time.Duration, started chan<- error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	defer close(b.done)
+
+	activeBridge = b
+	C.NimMain()
+
+	var privKey *C.uint8_t
+	if opts.PrivateKey != nil {
+		privKey = (*C.uint8_t)(C.CBytes(opts.PrivateKey))
+		defer C.free(unsafe.Pointer(privKey))
+	}
+
+	ok := C.nimbus_start(
+		C.int(opts.Port),
+		C.bool(opts.UseUDP),
+		C.bool(opts.LightNode),
+		C.double(opts.MinPow),
+		privKey,
+		C.bool(opts.VerifyNode),
+	)
+	if !bool(ok) {
+		started <- ErrStartFailed
+		return
+	}
+	started <- nil
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		default:
+		}
+		b.queue.drain()
+		pollStart := time.Now()
+		C.nimbus_poll()
+		b.recordPollDuration(time.Since(pollStart))
+		time.Sleep(pollInterval)
+	}
+}
+
+// submit runs fn on the Nim thread and returns its result. It is the single
+// choke point every Whisper method goes through.
+func (b *Bridge) submit(fn func() (interface{}, error)) (interface{}, error) {
+	if b.queue == nil {
+		return nil, ErrNotStarted
+	}
+	return b.queue.Submit(fn)
+}
+
+// submitTraced wraps submit in an OTel span named spanName, so the time
+// spent waiting for the Nim thread to drain the RoutineQueue is visible
+// alongside the rest of the call's latency. It is a plain submit when
+// EnableOTLP was never called.
+func (b *Bridge) submitTraced(spanName string, fn func() (interface{}, error)) (interface{}, error) {
+	_, span := b.startSpan(context.Background(), spanName)
+	defer span.End()
+	return b.submit(fn)
+}
+
+//export receiveHandler
+func receiveHandler(msg *C.received_message, udata unsafe.Pointer) {
+	filter, ok := gopointer.Restore(udata).(*Filter)
+	if !ok {
+		// udata outlived its Filter, or never pointed at one; drop the envelope.
+		return
+	}
+
+	received := ReceivedMessage{
+		Decoded:   C.GoBytes(unsafe.Pointer(msg.decoded), C.int(msg.decodedLen)),
+		Timestamp: uint32(msg.timestamp),
+		TTL:       uint32(msg.ttl),
+		PoW:       float64(msg.pow),
+	}
+	copy(received.Topic[:], (*[4]byte)(unsafe.Pointer(&msg.topic))[:])
+	if msg.source != nil {
+		received.Source = C.GoBytes(unsafe.Pointer(msg.source), 64)
+	}
+	if msg.hash != nil {
+		received.Hash = C.GoBytes(unsafe.Pointer(msg.hash), C.int(C.ID_LEN))
+	}
+	if msg.requestID != nil {
+		received.RequestID = C.GoBytes(unsafe.Pointer(msg.requestID), C.int(C.ID_LEN))
+	}
+
+	filter.deliver(received)
+	activeBridge.recordEnvelopeReceived()
+}
+
+// activeBridge is the Bridge whose poll loop is currently running. There is
+// only ever one, since libnimbus itself is a process-wide singleton; it lets
+// cgo callbacks that Nim hands a fixed signature (receiveHandler_cgo) reach
+// back into Bridge for metrics without threading extra state through udata.
+var activeBridge *Bridge
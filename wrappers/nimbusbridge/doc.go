@@ -0,0 +1,14 @@
+// Package nimbusbridge wraps the libnimbus C API in a Go-idiomatic,
+// thread-safe interface.
+//
+// Nimbus is single-threaded: every call into the C API (nimbus_poll,
+// nimbus_post, nimbus_subscribe_filter, ...) must happen on the same OS
+// thread that called NimMain. The examples under wrappers/ call into the
+// C API directly from whichever goroutine happens to run them, which is
+// unsafe as soon as more than one goroutine is involved.
+//
+// Bridge fixes this by locking one OS thread for Nim, running the poll
+// loop on it, and having every other goroutine submit work through a
+// RoutineQueue that the poll loop drains on each iteration. Whisper is
+// the public, goroutine-safe API built on top of that queue.
+package nimbusbridge
@@ -0,0 +1,28 @@
+package nimbusbridge
+
+import "errors"
+
+var (
+	// ErrAlreadyStarted is returned by Start when the bridge is already running.
+	ErrAlreadyStarted = errors.New("nimbusbridge: already started")
+	// ErrNotStarted is returned when an operation requires a running bridge.
+	ErrNotStarted = errors.New("nimbusbridge: not started")
+	// ErrStartFailed is returned when nimbus_start reports failure.
+	ErrStartFailed = errors.New("nimbusbridge: nimbus_start failed")
+	// ErrSubscribeFailed is returned when nimbus_subscribe_filter reports failure.
+	ErrSubscribeFailed = errors.New("nimbusbridge: nimbus_subscribe_filter failed")
+	// ErrPostFailed is returned when nimbus_post reports failure.
+	ErrPostFailed = errors.New("nimbusbridge: nimbus_post failed")
+	// ErrKeyPairFailed is returned when nimbus_new_keypair reports failure.
+	ErrKeyPairFailed = errors.New("nimbusbridge: nimbus_new_keypair failed")
+	// ErrSymKeyFailed is returned when nimbus_add_symkey_from_password reports failure.
+	ErrSymKeyFailed = errors.New("nimbusbridge: nimbus_add_symkey_from_password failed")
+	// ErrUnknownFilter is returned when an operation references a filter ID
+	// that was never subscribed, or was already unsubscribed.
+	ErrUnknownFilter = errors.New("nimbusbridge: unknown filter")
+	// ErrMarkTrustedPeerFailed is returned when nimbus_mark_trusted_peer reports failure.
+	ErrMarkTrustedPeerFailed = errors.New("nimbusbridge: nimbus_mark_trusted_peer failed")
+	// ErrMailserverTimeout is returned by RequestHistoricMessages when the
+	// mailserver never answers within the request's timeout.
+	ErrMailserverTimeout = errors.New("nimbusbridge: mailserver request timed out")
+)
@@ -0,0 +1,45 @@
+package nimbusbridge
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// Filter holds the envelopes received for a single subscription until the
+// owning goroutine collects them with GetFilterMessages. The cgo receive
+// handler appends to messages on the Nim thread; reads happen on whichever
+// goroutine calls GetFilterMessages, hence the mutex.
+type Filter struct {
+	id    []byte
+	udata unsafe.Pointer // the go-pointer handle passed to receiveHandler_cgo as udata
+
+	// mailserver is set when this Filter exists to collect envelopes for a
+	// RequestHistoricMessages call rather than a Subscribe call, so
+	// historicMessagesHandler can reach the pending request from the same
+	// udata handle receiveHandler already restores a *Filter from.
+	mailserver *mailserverRequest
+
+	mu       sync.Mutex
+	messages []ReceivedMessage
+}
+
+func newFilter(id []byte) *Filter {
+	return &Filter{id: id}
+}
+
+func (f *Filter) deliver(msg ReceivedMessage) {
+	f.mu.Lock()
+	f.messages = append(f.messages, msg)
+	f.mu.Unlock()
+}
+
+func (f *Filter) drain() []ReceivedMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.messages) == 0 {
+		return nil
+	}
+	drained := f.messages
+	f.messages = nil
+	return drained
+}
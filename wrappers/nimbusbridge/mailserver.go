@@ -0,0 +1,187 @@
+package nimbusbridge
+
+/*
+#include <stdlib.h>
+#include <stdbool.h>
+#include "libnimbus.h"
+
+void receiveHandler_cgo(received_message * msg, void* udata);
+void historicMessagesHandler_cgo(mailserver_request_completed * result, void* udata);
+*/
+import "C"
+
+import (
+	"time"
+	"unsafe"
+
+	gopointer "github.com/mattn/go-pointer"
+)
+
+// DefaultMailserverTimeout bounds how long RequestHistoricMessages waits for
+// a mailserver to answer before giving up.
+const DefaultMailserverTimeout = 30 * time.Second
+
+// MailserverRequest describes a request for historic envelopes sent while
+// the node was offline.
+type MailserverRequest struct {
+	PeerID   string
+	SymKeyID []byte
+	Topic    [4]byte
+	From     uint32
+	To       uint32
+	Cursor   []byte
+	Limit    uint32
+	Timeout  time.Duration
+}
+
+type mailserverResult struct {
+	cursor []byte
+	err    error
+}
+
+// mailserverRequest tracks a single in-flight request, keyed by the request
+// ID Nim echoes back on every delivered envelope and on completion.
+type mailserverRequest struct {
+	bridge *Bridge
+	filter *Filter
+	done   chan mailserverResult
+}
+
+// RequestHistoricMessages asks the mailserver at req.PeerID for envelopes
+// matching req.Topic in the [req.From, req.To] range, starting from
+// req.Cursor. Envelopes are delivered through the same Filter machinery as
+// Subscribe; the returned cursor can be passed back in to paginate further.
+func (b *Bridge) RequestHistoricMessages(req MailserverRequest) ([]byte, error) {
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = DefaultMailserverTimeout
+	}
+
+	result, err := b.submitTraced("nimbusbridge.RequestHistoricMessages", func() (interface{}, error) {
+		peerIDC := C.CString(req.PeerID)
+		defer C.free(unsafe.Pointer(peerIDC))
+
+		symKeyIDC := (*C.uint8_t)(C.CBytes(req.SymKeyID))
+		defer C.free(unsafe.Pointer(symKeyIDC))
+
+		var cursorC *C.uint8_t
+		if req.Cursor != nil {
+			cursorC = (*C.uint8_t)(C.CBytes(req.Cursor))
+			defer C.free(unsafe.Pointer(cursorC))
+		}
+
+		requestIDBuf := C.malloc(C.size_t(IDLen))
+		defer C.free(requestIDBuf)
+
+		filter := newFilter(nil)
+		pending := &mailserverRequest{bridge: b, filter: filter, done: make(chan mailserverResult, 1)}
+		filter.mailserver = pending
+		// Save the *Filter itself, not pending: receiveHandler always
+		// restores udata as a *Filter, for Subscribe and for
+		// RequestHistoricMessages alike, so both handlers must be able to
+		// share this exact handle.
+		udata := gopointer.Save(filter)
+		filter.udata = udata
+
+		var topic C.topic_t
+		copy((*[4]byte)(unsafe.Pointer(&topic))[:], req.Topic[:])
+
+		ok := C.nimbus_request_historic_messages(
+			peerIDC,
+			topic,
+			C.uint32_t(req.From),
+			C.uint32_t(req.To),
+			symKeyIDC,
+			cursorC,
+			C.size_t(len(req.Cursor)),
+			C.uint32_t(req.Limit),
+			(C.received_msg_handler)(unsafe.Pointer(C.receiveHandler_cgo)),
+			(C.mailserver_completed_handler)(unsafe.Pointer(C.historicMessagesHandler_cgo)),
+			udata,
+			(*C.uint8_t)(requestIDBuf),
+		)
+		if !bool(ok) {
+			gopointer.Unref(udata)
+			return nil, ErrStartFailed
+		}
+
+		requestID := C.GoBytes(requestIDBuf, C.int(IDLen))
+		filter.id = requestID
+		b.filters[string(requestID)] = filter
+		b.mailservers[string(requestID)] = pending
+		return pending, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	pending := result.(*mailserverRequest)
+
+	select {
+	case res := <-pending.done:
+		return res.cursor, res.err
+	case <-time.After(timeout):
+		b.abandonMailserverRequest(pending)
+		return nil, ErrMailserverTimeout
+	}
+}
+
+// abandonMailserverRequest cleans up the bookkeeping for a request that
+// timed out without the mailserver ever answering: historicMessagesHandler
+// will never run for it, so nothing else releases the filter/mailserver map
+// entries or the go-pointer handle passed as udata.
+func (b *Bridge) abandonMailserverRequest(pending *mailserverRequest) {
+	b.submit(func() (interface{}, error) {
+		requestID := string(pending.filter.id)
+		if _, ok := b.mailservers[requestID]; !ok {
+			// historicMessagesHandler already ran and cleaned up
+			// concurrently with the timeout firing; nothing to do.
+			return nil, nil
+		}
+		delete(b.filters, requestID)
+		delete(b.mailservers, requestID)
+		gopointer.Unref(pending.filter.udata)
+		return nil, nil
+	})
+}
+
+// MarkTrustedPeer marks enode as trusted. Mailservers must be trusted peers
+// before their p2p-direct envelopes (historic messages delivered outside the
+// normal gossip flow) are accepted.
+func (b *Bridge) MarkTrustedPeer(enode string) error {
+	_, err := b.submit(func() (interface{}, error) {
+		enodeC := C.CString(enode)
+		defer C.free(unsafe.Pointer(enodeC))
+		if !bool(C.nimbus_mark_trusted_peer(enodeC)) {
+			return nil, ErrMarkTrustedPeerFailed
+		}
+		return nil, nil
+	})
+	return err
+}
+
+//export historicMessagesHandler
+func historicMessagesHandler(result *C.mailserver_request_completed, udata unsafe.Pointer) {
+	filter, ok := gopointer.Restore(udata).(*Filter)
+	if !ok || filter.mailserver == nil {
+		return
+	}
+	pending := filter.mailserver
+	defer gopointer.Unref(udata)
+
+	var res mailserverResult
+	if result.errorMsg != nil {
+		res.err = &mailserverError{message: C.GoString(result.errorMsg)}
+	} else if result.cursorLen > 0 {
+		res.cursor = C.GoBytes(unsafe.Pointer(result.cursor), C.int(result.cursorLen))
+	}
+
+	requestID := string(pending.filter.id)
+	delete(pending.bridge.filters, requestID)
+	delete(pending.bridge.mailservers, requestID)
+
+	pending.done <- res
+}
+
+type mailserverError struct{ message string }
+
+func (e *mailserverError) Error() string { return "nimbusbridge: mailserver: " + e.message }
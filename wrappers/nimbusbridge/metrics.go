@@ -0,0 +1,172 @@
+package nimbusbridge
+
+/*
+#include "libnimbus.h"
+*/
+import "C"
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// Compression selects the wire compression used by the OTLP gRPC exporters.
+// Only the codecs registered with gRPC's encoding package are listed here;
+// WithCompressor silently drops every export if asked for a codec nobody
+// registered, so snappy/zstd aren't offered until this package registers
+// real implementations for them.
+type Compression string
+
+const (
+	CompressionNone Compression = ""
+	CompressionGzip Compression = gzip.Name
+)
+
+const instrumentationName = "github.com/status-im/nimbus-eth1/wrappers/nimbusbridge"
+
+// metrics holds every instrument the bridge reports. It is nil until
+// EnableOTLP is called, at which point every recordX helper becomes a no-op
+// check rather than a nil-pointer panic.
+type metrics struct {
+	tracer trace.Tracer
+
+	envelopesReceived metric.Int64Counter
+	envelopesPosted   metric.Int64Counter
+	envelopesRejected metric.Int64Counter
+	peers             metric.Int64ObservableGauge
+	queueDepth        metric.Int64ObservableGauge
+	pollDuration      metric.Float64Histogram
+}
+
+// EnableOTLP registers OTLP gRPC exporters for traces and metrics against
+// endpoint, and starts reporting queue depth, envelope counters and poll
+// latency for this Bridge. It must be called before Start: the poll loop
+// reads b.metrics without synchronization once running, so setting it
+// concurrently would race.
+func (b *Bridge) EnableOTLP(endpoint string, headers map[string]string, compression Compression) error {
+	if b.queue != nil {
+		return ErrAlreadyStarted
+	}
+
+	ctx := context.Background()
+
+	traceExporter, err := otlptracegrpc.New(ctx,
+		otlptracegrpc.WithEndpoint(endpoint),
+		otlptracegrpc.WithHeaders(headers),
+		otlptracegrpc.WithCompressor(string(compression)),
+	)
+	if err != nil {
+		return err
+	}
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(traceExporter))
+	otel.SetTracerProvider(tracerProvider)
+
+	metricExporter, err := otlpmetricgrpc.New(ctx,
+		otlpmetricgrpc.WithEndpoint(endpoint),
+		otlpmetricgrpc.WithHeaders(headers),
+		otlpmetricgrpc.WithCompressor(string(compression)),
+	)
+	if err != nil {
+		return err
+	}
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+	)
+	otel.SetMeterProvider(meterProvider)
+
+	meter := meterProvider.Meter(instrumentationName)
+	m := &metrics{tracer: tracerProvider.Tracer(instrumentationName)}
+
+	if m.envelopesReceived, err = meter.Int64Counter("nimbus_envelopes_received_total"); err != nil {
+		return err
+	}
+	if m.envelopesPosted, err = meter.Int64Counter("nimbus_envelopes_posted_total"); err != nil {
+		return err
+	}
+	if m.envelopesRejected, err = meter.Int64Counter("nimbus_envelopes_rejected_total"); err != nil {
+		return err
+	}
+	if m.pollDuration, err = meter.Float64Histogram("nimbus_poll_duration_seconds"); err != nil {
+		return err
+	}
+	if m.peers, err = meter.Int64ObservableGauge("nimbus_peers"); err != nil {
+		return err
+	}
+	if m.queueDepth, err = meter.Int64ObservableGauge("nimbus_routine_queue_depth"); err != nil {
+		return err
+	}
+	if _, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(m.queueDepth, int64(b.queue.depth()))
+		return nil
+	}, m.queueDepth); err != nil {
+		return err
+	}
+	if _, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		// nimbus_peer_count, like every other call into the C API, must run
+		// on the single OS thread Nim was started on: route it through
+		// submit instead of calling it directly from whatever goroutine
+		// OTel's periodic reader uses to invoke this callback.
+		count, err := b.submit(func() (interface{}, error) {
+			return int64(C.nimbus_peer_count()), nil
+		})
+		if err != nil {
+			return err
+		}
+		o.ObserveInt64(m.peers, count.(int64))
+		return nil
+	}, m.peers); err != nil {
+		return err
+	}
+
+	b.metrics = m
+	return nil
+}
+
+func (b *Bridge) recordEnvelopeReceived() {
+	if b == nil || b.metrics == nil {
+		return
+	}
+	b.metrics.envelopesReceived.Add(context.Background(), 1)
+}
+
+func (b *Bridge) recordEnvelopePosted() {
+	if b.metrics == nil {
+		return
+	}
+	b.metrics.envelopesPosted.Add(context.Background(), 1)
+}
+
+func (b *Bridge) recordEnvelopeRejected(reason string) {
+	if b.metrics == nil {
+		return
+	}
+	b.metrics.envelopesRejected.Add(context.Background(), 1, metric.WithAttributes(attribute.String("reason", reason)))
+}
+
+func (b *Bridge) recordPollDuration(d time.Duration) {
+	if b.metrics == nil {
+		return
+	}
+	b.metrics.pollDuration.Record(context.Background(), d.Seconds())
+}
+
+// startSpan wraps a RoutineQueue submit-and-wait with an OTel span named
+// name, so app-level calls can be correlated with Nim-side processing
+// latency. It is a no-op (returning the background context) until
+// EnableOTLP has been called.
+func (b *Bridge) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	if b.metrics == nil {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return b.metrics.tracer.Start(ctx, name)
+}
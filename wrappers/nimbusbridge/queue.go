@@ -0,0 +1,55 @@
+package nimbusbridge
+
+// routine is a unit of work that must run on the Nim thread. fn is executed
+// by the poll loop, and its result/error are delivered back on reply.
+type routine struct {
+	fn    func() (interface{}, error)
+	reply chan routineResult
+}
+
+type routineResult struct {
+	value interface{}
+	err   error
+}
+
+// RoutineQueue lets goroutines enqueue closures that must run on the single
+// OS thread Nim was started on, and block until that thread has executed
+// them. It is drained by the poll loop inside Bridge.run, never by callers.
+type RoutineQueue struct {
+	routines chan routine
+}
+
+// NewRoutineQueue creates a RoutineQueue with the given buffer size. A
+// buffered channel lets callers enqueue without waiting for the poll loop to
+// wake up, while still bounding how much work can pile up.
+func NewRoutineQueue(buffer int) *RoutineQueue {
+	return &RoutineQueue{routines: make(chan routine, buffer)}
+}
+
+// Submit enqueues fn and blocks until the Nim thread has run it, returning
+// whatever fn returned.
+func (q *RoutineQueue) Submit(fn func() (interface{}, error)) (interface{}, error) {
+	reply := make(chan routineResult, 1)
+	q.routines <- routine{fn: fn, reply: reply}
+	result := <-reply
+	return result.value, result.err
+}
+
+// drain runs every routine currently queued, without blocking for more to
+// arrive. It is only safe to call from the Nim thread.
+func (q *RoutineQueue) drain() {
+	for {
+		select {
+		case r := <-q.routines:
+			value, err := r.fn()
+			r.reply <- routineResult{value: value, err: err}
+		default:
+			return
+		}
+	}
+}
+
+// depth reports how many routines are currently queued, for metrics.
+func (q *RoutineQueue) depth() int {
+	return len(q.routines)
+}
@@ -0,0 +1,112 @@
+package nimbusbridge
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRoutineQueueSubmitDrain(t *testing.T) {
+	q := NewRoutineQueue(4)
+
+	done := make(chan struct{})
+	var value interface{}
+	var err error
+	go func() {
+		value, err = q.Submit(func() (interface{}, error) {
+			return 42, nil
+		})
+		close(done)
+	}()
+
+	// Submit blocks until something calls drain; give the goroutine a
+	// moment to enqueue before draining, mirroring how the poll loop only
+	// drains once per iteration rather than spinning.
+	time.Sleep(10 * time.Millisecond)
+	q.drain()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Submit did not return after drain")
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != 42 {
+		t.Fatalf("got %v, want 42", value)
+	}
+}
+
+func TestRoutineQueueSubmitPropagatesError(t *testing.T) {
+	q := NewRoutineQueue(1)
+	wantErr := errors.New("boom")
+
+	done := make(chan struct{})
+	var err error
+	go func() {
+		_, err = q.Submit(func() (interface{}, error) {
+			return nil, wantErr
+		})
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	q.drain()
+	<-done
+
+	if err != wantErr {
+		t.Fatalf("got %v, want %v", err, wantErr)
+	}
+}
+
+func TestRoutineQueueDrainRunsEverythingQueued(t *testing.T) {
+	q := NewRoutineQueue(8)
+
+	const n = 5
+	results := make(chan int, n)
+	for i := 0; i < n; i++ {
+		i := i
+		go func() {
+			v, _ := q.Submit(func() (interface{}, error) {
+				return i, nil
+			})
+			results <- v.(int)
+		}()
+	}
+
+	// Let every goroutine get its routine onto the channel before draining
+	// once, so a single drain call is exercised against a full queue.
+	time.Sleep(20 * time.Millisecond)
+	q.drain()
+
+	seen := make(map[int]bool)
+	for i := 0; i < n; i++ {
+		select {
+		case v := <-results:
+			seen[v] = true
+		case <-time.After(time.Second):
+			t.Fatalf("only received %d of %d results", len(seen), n)
+		}
+	}
+	if len(seen) != n {
+		t.Fatalf("got %d distinct results, want %d", len(seen), n)
+	}
+}
+
+func TestRoutineQueueDepth(t *testing.T) {
+	q := NewRoutineQueue(4)
+	if got := q.depth(); got != 0 {
+		t.Fatalf("depth() = %d, want 0", got)
+	}
+
+	q.routines <- routine{fn: func() (interface{}, error) { return nil, nil }, reply: make(chan routineResult, 1)}
+	if got := q.depth(); got != 1 {
+		t.Fatalf("depth() = %d, want 1", got)
+	}
+
+	q.drain()
+	if got := q.depth(); got != 0 {
+		t.Fatalf("depth() = %d, want 0 after drain", got)
+	}
+}
@@ -0,0 +1,402 @@
+package nimbusbridge
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// RPCOptions configures StartRPC.
+type RPCOptions struct {
+	Host string
+	Port int
+	// APIs lists the namespaces to expose, e.g. "shh" and/or "waku". Both
+	// namespaces currently serve the same handlers.
+	APIs []string
+}
+
+// rpcRequest is a JSON-RPC 2.0 request.
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// rpcSubscriptionNotification mirrors the eth_subscription notification
+// shape used by standard Ethereum JSON-RPC WebSocket transports.
+type rpcSubscriptionNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  struct {
+		Subscription string      `json:"subscription"`
+		Result       interface{} `json:"result"`
+	} `json:"params"`
+}
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// StartRPC stands up an HTTP + WebSocket JSON-RPC server translating
+// shh_*/waku_* methods into calls against the bridge's Whisper API. HTTP
+// requests are answered once; WebSocket connections additionally receive
+// eth_subscription-style notifications for shh_subscribe filters.
+func (b *Bridge) StartRPC(opts RPCOptions) error {
+	addr := fmt.Sprintf("%s:%d", opts.Host, opts.Port)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			b.serveWS(w, r)
+			return
+		}
+		b.serveHTTP(w, r)
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	return nil
+}
+
+func (b *Bridge) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := b.handleRPC(req, nil)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (b *Bridge) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	session := &wsSession{done: make(chan struct{})}
+	defer close(session.done)
+
+	var writeMu sync.Mutex
+	session.notify = func(subID string, result interface{}) {
+		note := rpcSubscriptionNotification{JSONRPC: "2.0", Method: "shh_subscription"}
+		note.Params.Subscription = subID
+		note.Params.Result = result
+
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.WriteJSON(note)
+	}
+
+	for {
+		var req rpcRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		resp := b.handleRPC(req, session)
+
+		writeMu.Lock()
+		err := conn.WriteJSON(resp)
+		writeMu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// wsSession is the per-connection state shh_subscribe needs: somewhere to
+// push notifications, and a signal for when the connection is gone so any
+// streamFilter goroutines it started can stop.
+type wsSession struct {
+	notify func(subID string, result interface{})
+	done   chan struct{}
+}
+
+func (b *Bridge) handleRPC(req rpcRequest, session *wsSession) rpcResponse {
+	resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+
+	result, err := b.dispatchRPC(req.Method, req.Params, session)
+	if err != nil {
+		resp.Error = &rpcError{Code: -32000, Message: err.Error()}
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+func (b *Bridge) dispatchRPC(method string, params json.RawMessage, session *wsSession) (interface{}, error) {
+	switch method {
+	case "shh_newKeyPair", "waku_newKeyPair":
+		id, err := b.AddKeyPair()
+		return hex.EncodeToString(id), err
+
+	case "shh_addSymKey", "waku_addSymKey":
+		var p struct {
+			Password string `json:"password"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		id, err := b.AddSymKeyFromPassword(p.Password)
+		return hex.EncodeToString(id), err
+
+	case "shh_newMessageFilter", "waku_newMessageFilter":
+		opts, err := decodeSubscribeOptions(params)
+		if err != nil {
+			return nil, err
+		}
+		id, err := b.Subscribe(opts)
+		return hex.EncodeToString(id), err
+
+	case "shh_getFilterMessages", "waku_getFilterMessages":
+		var p [1]string
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		id, err := hex.DecodeString(p[0])
+		if err != nil {
+			return nil, err
+		}
+		messages, err := b.GetFilterMessages(id)
+		return encodeReceivedMessages(messages), err
+
+	case "shh_post", "waku_post":
+		msg, err := decodeMessage(params)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := b.Post(msg)
+		return hex.EncodeToString(hash), err
+
+	case "shh_markTrustedPeer", "waku_markTrustedPeer":
+		var p [1]string
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		return nil, b.MarkTrustedPeer(p[0])
+
+	case "shh_requestMessages", "waku_requestMessages":
+		req, err := decodeMailserverRequest(params)
+		if err != nil {
+			return nil, err
+		}
+		cursor, err := b.RequestHistoricMessages(req)
+		return hex.EncodeToString(cursor), err
+
+	case "shh_subscribe", "waku_subscribe":
+		if session == nil {
+			return nil, fmt.Errorf("nimbusbridge: %s requires a WebSocket connection", method)
+		}
+		opts, err := decodeSubscribeOptions(params)
+		if err != nil {
+			return nil, err
+		}
+		id, err := b.Subscribe(opts)
+		if err != nil {
+			return nil, err
+		}
+		subID := hex.EncodeToString(id)
+		go b.streamFilter(id, subID, session)
+		return subID, nil
+
+	case "shh_unsubscribe", "waku_unsubscribe":
+		var p [1]string
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		id, err := hex.DecodeString(p[0])
+		if err != nil {
+			return nil, err
+		}
+		if err := b.Unsubscribe(id); err != nil {
+			return false, err
+		}
+		return true, nil
+
+	default:
+		return nil, fmt.Errorf("nimbusbridge: unknown method %q", method)
+	}
+}
+
+func decodeSubscribeOptions(params json.RawMessage) (SubscribeOptions, error) {
+	var p struct {
+		SymKeyID    string  `json:"symKeyID"`
+		SigIdentity string  `json:"sig"`
+		MinPow      float64 `json:"minPow"`
+		Topic       string  `json:"topic"`
+		AllowP2P    bool    `json:"allowP2P"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return SubscribeOptions{}, err
+	}
+
+	opts := SubscribeOptions{MinPow: p.MinPow, AllowP2P: p.AllowP2P}
+	var err error
+	if p.SymKeyID != "" {
+		if opts.SymKeyID, err = hex.DecodeString(p.SymKeyID); err != nil {
+			return SubscribeOptions{}, err
+		}
+	}
+	if p.SigIdentity != "" {
+		if opts.SigIdentity, err = hex.DecodeString(p.SigIdentity); err != nil {
+			return SubscribeOptions{}, err
+		}
+	}
+	if p.Topic != "" {
+		topic, err := hex.DecodeString(p.Topic)
+		if err != nil {
+			return SubscribeOptions{}, err
+		}
+		copy(opts.Topic[:], topic)
+	}
+	return opts, nil
+}
+
+func decodeMessage(params json.RawMessage) (Message, error) {
+	var p struct {
+		SymKeyID  string  `json:"symKeyID"`
+		Sig       string  `json:"sig"`
+		Payload   string  `json:"payload"`
+		Padding   string  `json:"padding"`
+		Topic     string  `json:"topic"`
+		TTL       uint32  `json:"ttl"`
+		PowTime   float64 `json:"powTime"`
+		PowTarget float64 `json:"powTarget"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return Message{}, err
+	}
+
+	msg := Message{TTL: p.TTL, PowTime: p.PowTime, PowTarget: p.PowTarget}
+	var err error
+	if p.SymKeyID != "" {
+		if msg.SymKeyID, err = hex.DecodeString(p.SymKeyID); err != nil {
+			return Message{}, err
+		}
+	}
+	if p.Sig != "" {
+		if msg.SigIdentity, err = hex.DecodeString(p.Sig); err != nil {
+			return Message{}, err
+		}
+	}
+	if msg.Payload, err = hex.DecodeString(p.Payload); err != nil {
+		return Message{}, err
+	}
+	if p.Padding != "" {
+		if msg.Padding, err = hex.DecodeString(p.Padding); err != nil {
+			return Message{}, err
+		}
+	}
+	if p.Topic != "" {
+		topic, err := hex.DecodeString(p.Topic)
+		if err != nil {
+			return Message{}, err
+		}
+		copy(msg.Topic[:], topic)
+	}
+	return msg, nil
+}
+
+func decodeMailserverRequest(params json.RawMessage) (MailserverRequest, error) {
+	var p struct {
+		PeerID   string `json:"peerID"`
+		SymKeyID string `json:"symKeyID"`
+		Topic    string `json:"topic"`
+		From     uint32 `json:"from"`
+		To       uint32 `json:"to"`
+		Cursor   string `json:"cursor"`
+		Limit    uint32 `json:"limit"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return MailserverRequest{}, err
+	}
+
+	req := MailserverRequest{PeerID: p.PeerID, From: p.From, To: p.To, Limit: p.Limit}
+	var err error
+	if req.SymKeyID, err = hex.DecodeString(p.SymKeyID); err != nil {
+		return MailserverRequest{}, err
+	}
+	if p.Topic != "" {
+		topic, err := hex.DecodeString(p.Topic)
+		if err != nil {
+			return MailserverRequest{}, err
+		}
+		copy(req.Topic[:], topic)
+	}
+	if p.Cursor != "" {
+		if req.Cursor, err = hex.DecodeString(p.Cursor); err != nil {
+			return MailserverRequest{}, err
+		}
+	}
+	return req, nil
+}
+
+func encodeReceivedMessages(messages []ReceivedMessage) []map[string]interface{} {
+	out := make([]map[string]interface{}, len(messages))
+	for i, m := range messages {
+		out[i] = map[string]interface{}{
+			"payload":   hex.EncodeToString(m.Decoded),
+			"sig":       hex.EncodeToString(m.Source),
+			"timestamp": m.Timestamp,
+			"ttl":       m.TTL,
+			"topic":     hex.EncodeToString(m.Topic[:]),
+			"pow":       m.PoW,
+			"hash":      hex.EncodeToString(m.Hash),
+		}
+	}
+	return out
+}
+
+// streamFilter polls filterID's buffered envelopes and pushes each one to
+// session.notify as a shh_subscription notification. It stops, and
+// unsubscribes filterID, as soon as either GetFilterMessages starts failing
+// (the filter was explicitly unsubscribed, or the bridge stopped) or
+// session.done closes (the WebSocket connection it was streaming to is
+// gone), whichever happens first.
+func (b *Bridge) streamFilter(filterID []byte, subID string, session *wsSession) {
+	defer b.Unsubscribe(filterID)
+
+	for {
+		select {
+		case <-session.done:
+			return
+		default:
+		}
+
+		messages, err := b.GetFilterMessages(filterID)
+		if err != nil {
+			return
+		}
+		for _, m := range encodeReceivedMessages(messages) {
+			session.notify(subID, m)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
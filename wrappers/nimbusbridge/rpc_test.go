@@ -0,0 +1,97 @@
+package nimbusbridge
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodeSubscribeOptions(t *testing.T) {
+	params := json.RawMessage(`{"symKeyID":"aabb","sig":"ccdd","minPow":0.002,"topic":"deadbeef","allowP2P":true}`)
+
+	opts, err := decodeSubscribeOptions(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hex.EncodeToString(opts.SymKeyID) != "aabb" {
+		t.Errorf("SymKeyID = %x, want aabb", opts.SymKeyID)
+	}
+	if hex.EncodeToString(opts.SigIdentity) != "ccdd" {
+		t.Errorf("SigIdentity = %x, want ccdd", opts.SigIdentity)
+	}
+	if opts.MinPow != 0.002 {
+		t.Errorf("MinPow = %v, want 0.002", opts.MinPow)
+	}
+	if !opts.AllowP2P {
+		t.Error("AllowP2P = false, want true")
+	}
+	if hex.EncodeToString(opts.Topic[:]) != "deadbeef" {
+		t.Errorf("Topic = %x, want deadbeef", opts.Topic)
+	}
+}
+
+func TestDecodeSubscribeOptionsRejectsBadHex(t *testing.T) {
+	_, err := decodeSubscribeOptions(json.RawMessage(`{"symKeyID":"not-hex"}`))
+	if err == nil {
+		t.Fatal("expected an error for invalid hex, got nil")
+	}
+}
+
+func TestDecodeMessage(t *testing.T) {
+	params := json.RawMessage(`{"symKeyID":"aabb","sig":"ccdd","payload":"68656c6c6f","padding":"00","topic":"deadbeef","ttl":20,"powTime":1.0,"powTarget":0.002}`)
+
+	msg, err := decodeMessage(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(msg.Payload) != "hello" {
+		t.Errorf("Payload = %q, want %q", msg.Payload, "hello")
+	}
+	if msg.TTL != 20 {
+		t.Errorf("TTL = %d, want 20", msg.TTL)
+	}
+	if msg.PowTarget != 0.002 {
+		t.Errorf("PowTarget = %v, want 0.002", msg.PowTarget)
+	}
+	if hex.EncodeToString(msg.Topic[:]) != "deadbeef" {
+		t.Errorf("Topic = %x, want deadbeef", msg.Topic)
+	}
+}
+
+func TestDecodeMailserverRequest(t *testing.T) {
+	params := json.RawMessage(`{"peerID":"enode://abc","symKeyID":"aabb","topic":"deadbeef","from":1,"to":2,"cursor":"ff","limit":10}`)
+
+	req, err := decodeMailserverRequest(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.PeerID != "enode://abc" {
+		t.Errorf("PeerID = %q, want %q", req.PeerID, "enode://abc")
+	}
+	if req.From != 1 || req.To != 2 || req.Limit != 10 {
+		t.Errorf("From/To/Limit = %d/%d/%d, want 1/2/10", req.From, req.To, req.Limit)
+	}
+	if hex.EncodeToString(req.Cursor) != "ff" {
+		t.Errorf("Cursor = %x, want ff", req.Cursor)
+	}
+}
+
+func TestEncodeReceivedMessages(t *testing.T) {
+	messages := []ReceivedMessage{
+		{Decoded: []byte("hello"), Source: []byte{0xaa}, Timestamp: 123, TTL: 20, PoW: 0.01, Hash: []byte{0xbb}},
+	}
+
+	encoded := encodeReceivedMessages(messages)
+	if len(encoded) != 1 {
+		t.Fatalf("got %d messages, want 1", len(encoded))
+	}
+	if encoded[0]["payload"] != hex.EncodeToString([]byte("hello")) {
+		t.Errorf("payload = %v, want %x", encoded[0]["payload"], "hello")
+	}
+	if encoded[0]["sig"] != "aa" {
+		t.Errorf("sig = %v, want aa", encoded[0]["sig"])
+	}
+	if encoded[0]["hash"] != "bb" {
+		t.Errorf("hash = %v, want bb", encoded[0]["hash"])
+	}
+}
@@ -0,0 +1,80 @@
+package nimbusbridge
+
+// IDLen is the length, in bytes, of filter, key and peer IDs returned by the
+// Nim side. It mirrors the C API's ID_LEN constant.
+const IDLen = 32
+
+// SubscribeOptions configures a new filter. SymKeyID and SigIdentity are
+// mutually exclusive in the same way the underlying filter_options struct's
+// symKeyID/sigIdentity fields are: set the one matching the envelopes you
+// expect to receive.
+type SubscribeOptions struct {
+	SymKeyID    []byte
+	SigIdentity []byte
+	MinPow      float64
+	Topic       [4]byte
+	AllowP2P    bool
+}
+
+// Message is an envelope payload ready to be posted.
+type Message struct {
+	SymKeyID    []byte
+	SigIdentity []byte
+	Payload     []byte
+	Padding     []byte
+	Topic       [4]byte
+	TTL         uint32
+	PowTime     float64
+	PowTarget   float64
+}
+
+// ReceivedMessage is a decoded envelope delivered to a subscriber.
+type ReceivedMessage struct {
+	Decoded   []byte
+	Source    []byte
+	Timestamp uint32
+	TTL       uint32
+	Topic     [4]byte
+	PoW       float64
+	Hash      []byte
+	// RequestID identifies the RequestHistoricMessages call this envelope
+	// answers, and is empty for envelopes delivered through Subscribe.
+	RequestID []byte
+}
+
+// Whisper is the goroutine-safe API exposed by Bridge. Every method submits
+// its work to the Bridge's RoutineQueue and blocks until the Nim thread has
+// run it, so it is safe to call concurrently from any goroutine.
+type Whisper interface {
+	// Subscribe installs a new filter and returns its ID. Received envelopes
+	// are buffered on the returned Filter until GetFilterMessages is called.
+	Subscribe(opts SubscribeOptions) (filterID []byte, err error)
+	// Unsubscribe removes a previously installed filter.
+	Unsubscribe(filterID []byte) error
+	// GetFilterMessages drains and returns the envelopes buffered for filterID
+	// since the last call.
+	GetFilterMessages(filterID []byte) ([]ReceivedMessage, error)
+	// Post enqueues an envelope and returns its hash.
+	Post(msg Message) (hash []byte, err error)
+	// AddKeyPair generates a new asymmetric keypair and returns its ID.
+	AddKeyPair() (keyID []byte, err error)
+	// AddSymKeyFromPassword derives a symmetric key from password and
+	// returns its ID.
+	AddSymKeyFromPassword(password string) (keyID []byte, err error)
+	// AddPeer connects to the given enode.
+	AddPeer(enode string) error
+	// RequestHistoricMessages asks a mailserver for envelopes posted while
+	// this node was offline, and returns a cursor for paginating further.
+	RequestHistoricMessages(req MailserverRequest) (cursor []byte, err error)
+	// MarkTrustedPeer marks enode as trusted, which mailservers must be
+	// before their historic envelopes are accepted.
+	MarkTrustedPeer(enode string) error
+	// MinPow returns the minimum proof of work required by this node.
+	MinPow() (float64, error)
+	// SetMinPow updates the minimum proof of work required by this node.
+	SetMinPow(pow float64) error
+	// BloomFilter returns the node's current bloom filter.
+	BloomFilter() ([]byte, error)
+	// SetBloomFilter replaces the node's bloom filter.
+	SetBloomFilter(bloom []byte) error
+}
@@ -0,0 +1,240 @@
+package nimbusbridge
+
+/*
+#include <stdlib.h>
+#include <stdbool.h>
+#include "libnimbus.h"
+
+void receiveHandler_cgo(received_message * msg, void* udata);
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	gopointer "github.com/mattn/go-pointer"
+)
+
+// Subscribe implements Whisper.
+func (b *Bridge) Subscribe(opts SubscribeOptions) ([]byte, error) {
+	result, err := b.submitTraced("nimbusbridge.Subscribe", func() (interface{}, error) {
+		var symKeyIDC, sigIdentityC *C.uint8_t
+		if opts.SymKeyID != nil {
+			symKeyIDC = (*C.uint8_t)(C.CBytes(opts.SymKeyID))
+			defer C.free(unsafe.Pointer(symKeyIDC))
+		}
+		if opts.SigIdentity != nil {
+			sigIdentityC = (*C.uint8_t)(C.CBytes(opts.SigIdentity))
+			defer C.free(unsafe.Pointer(sigIdentityC))
+		}
+
+		cOpts := C.filter_options{
+			symKeyID:    symKeyIDC,
+			sigIdentity: sigIdentityC,
+			minPow:      C.double(opts.MinPow),
+			allowP2P:    C.bool(opts.AllowP2P),
+		}
+		copy((*[4]byte)(unsafe.Pointer(&cOpts.topic))[:], opts.Topic[:])
+
+		idBuf := C.malloc(C.size_t(IDLen))
+		defer C.free(idBuf)
+
+		filterID := make([]byte, IDLen)
+		filter := newFilter(filterID)
+		udata := gopointer.Save(filter)
+
+		ok := C.nimbus_subscribe_filter(&cOpts,
+			(C.received_msg_handler)(unsafe.Pointer(C.receiveHandler_cgo)),
+			udata, (*C.uint8_t)(idBuf))
+		if !bool(ok) {
+			gopointer.Unref(udata)
+			return nil, ErrSubscribeFailed
+		}
+
+		copy(filterID, C.GoBytes(idBuf, C.int(IDLen)))
+		filter.id = filterID
+		filter.udata = udata
+		b.filters[string(filterID)] = filter
+		return filterID, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]byte), nil
+}
+
+// Unsubscribe implements Whisper.
+func (b *Bridge) Unsubscribe(filterID []byte) error {
+	_, err := b.submit(func() (interface{}, error) {
+		filter, ok := b.filters[string(filterID)]
+		if !ok {
+			return nil, ErrUnknownFilter
+		}
+		idC := (*C.uint8_t)(C.CBytes(filterID))
+		defer C.free(unsafe.Pointer(idC))
+		C.nimbus_unsubscribe_filter(idC)
+		gopointer.Unref(filter.udata)
+		delete(b.filters, string(filterID))
+		return nil, nil
+	})
+	return err
+}
+
+// GetFilterMessages implements Whisper.
+func (b *Bridge) GetFilterMessages(filterID []byte) ([]ReceivedMessage, error) {
+	result, err := b.submit(func() (interface{}, error) {
+		filter, ok := b.filters[string(filterID)]
+		if !ok {
+			return nil, ErrUnknownFilter
+		}
+		return filter.drain(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, nil
+	}
+	return result.([]ReceivedMessage), nil
+}
+
+// Post implements Whisper.
+func (b *Bridge) Post(msg Message) ([]byte, error) {
+	result, err := b.submitTraced("nimbusbridge.Post", func() (interface{}, error) {
+		var symKeyIDC, sigIdentityC *C.uint8_t
+		if msg.SymKeyID != nil {
+			symKeyIDC = (*C.uint8_t)(C.CBytes(msg.SymKeyID))
+			defer C.free(unsafe.Pointer(symKeyIDC))
+		}
+		if msg.SigIdentity != nil {
+			sigIdentityC = (*C.uint8_t)(C.CBytes(msg.SigIdentity))
+			defer C.free(unsafe.Pointer(sigIdentityC))
+		}
+
+		cMsg := C.post_message{
+			symKeyID:  symKeyIDC,
+			sourceID:  sigIdentityC,
+			ttl:       C.uint32_t(msg.TTL),
+			powTarget: C.double(msg.PowTarget),
+			powTime:   C.double(msg.PowTime),
+		}
+		copy((*[4]byte)(unsafe.Pointer(&cMsg.topic))[:], msg.Topic[:])
+
+		payloadC := C.CBytes(msg.Payload)
+		defer C.free(payloadC)
+		cMsg.payload = (*C.uint8_t)(payloadC)
+		cMsg.payloadLen = C.size_t(len(msg.Payload))
+
+		if msg.Padding != nil {
+			paddingC := C.CBytes(msg.Padding)
+			defer C.free(paddingC)
+			cMsg.padding = (*C.uint8_t)(paddingC)
+			cMsg.paddingLen = C.size_t(len(msg.Padding))
+		}
+
+		hashBuf := C.malloc(C.size_t(IDLen))
+		defer C.free(hashBuf)
+
+		if !bool(C.nimbus_post(&cMsg, (*C.uint8_t)(hashBuf))) {
+			b.recordEnvelopeRejected("post_failed")
+			return nil, ErrPostFailed
+		}
+		b.recordEnvelopePosted()
+		return C.GoBytes(hashBuf, C.int(IDLen)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]byte), nil
+}
+
+// AddKeyPair implements Whisper.
+func (b *Bridge) AddKeyPair() ([]byte, error) {
+	result, err := b.submit(func() (interface{}, error) {
+		buf := C.malloc(C.size_t(IDLen))
+		defer C.free(buf)
+		if !bool(C.nimbus_new_keypair((*C.uint8_t)(buf))) {
+			return nil, ErrKeyPairFailed
+		}
+		return C.GoBytes(buf, C.int(IDLen)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]byte), nil
+}
+
+// AddSymKeyFromPassword implements Whisper.
+func (b *Bridge) AddSymKeyFromPassword(password string) ([]byte, error) {
+	result, err := b.submit(func() (interface{}, error) {
+		passwordC := C.CString(password)
+		defer C.free(unsafe.Pointer(passwordC))
+
+		buf := C.malloc(C.size_t(IDLen))
+		defer C.free(buf)
+		if !bool(C.nimbus_add_symkey_from_password(passwordC, (*C.uint8_t)(buf))) {
+			return nil, ErrSymKeyFailed
+		}
+		return C.GoBytes(buf, C.int(IDLen)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]byte), nil
+}
+
+// AddPeer implements Whisper.
+func (b *Bridge) AddPeer(enode string) error {
+	_, err := b.submit(func() (interface{}, error) {
+		enodeC := C.CString(enode)
+		defer C.free(unsafe.Pointer(enodeC))
+		C.nimbus_add_peer(enodeC)
+		return nil, nil
+	})
+	return err
+}
+
+// MinPow implements Whisper.
+func (b *Bridge) MinPow() (float64, error) {
+	result, err := b.submit(func() (interface{}, error) {
+		return float64(C.nimbus_get_min_pow()), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.(float64), nil
+}
+
+// SetMinPow implements Whisper.
+func (b *Bridge) SetMinPow(pow float64) error {
+	_, err := b.submit(func() (interface{}, error) {
+		C.nimbus_set_min_pow(C.double(pow))
+		return nil, nil
+	})
+	return err
+}
+
+// BloomFilter implements Whisper.
+func (b *Bridge) BloomFilter() ([]byte, error) {
+	result, err := b.submit(func() (interface{}, error) {
+		buf := C.malloc(C.size_t(C.BLOOM_FILTER_LEN))
+		defer C.free(buf)
+		C.nimbus_get_bloom_filter((*C.uint8_t)(buf))
+		return C.GoBytes(buf, C.int(C.BLOOM_FILTER_LEN)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]byte), nil
+}
+
+// SetBloomFilter implements Whisper.
+func (b *Bridge) SetBloomFilter(bloom []byte) error {
+	_, err := b.submit(func() (interface{}, error) {
+		bloomC := (*C.uint8_t)(C.CBytes(bloom))
+		defer C.free(unsafe.Pointer(bloomC))
+		C.nimbus_set_bloom_filter(bloomC)
+		return nil, nil
+	})
+	return err
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"container/list"
 	"encoding/hex"
 	"fmt"
 	"runtime"
+	"sync"
 	"time"
 	"unsafe"
+
+	gopointer "github.com/mattn/go-pointer"
 )
 
 /*
@@ -13,7 +17,7 @@ import (
 #include <stdbool.h>
 
 // Passing "-lnimbus" to the Go linker through "-extldflags" is not enough. We need it in here, for some reason.
-#cgo LDFLAGS: -Wl,-rpath,'$ORIGIN' -L${SRCDIR}/../build -lnimbus
+#cgo LDFLAGS: -Wl,-rpath,'$ORIGIN' -L${SRCDIR}/../../build -lnimbus
 #include "libnimbus.h"
 
 void receiveHandler_cgo(received_message * msg, void* udata); // Forward declaration.
@@ -25,17 +29,71 @@ func init() {
 	runtime.LockOSThread()
 }
 
+// Filter buffers the envelopes delivered to a single subscription. The cgo
+// receive handler appends to messages on the Nim thread; GetFilterMessages
+// drains it from whichever goroutine is consuming the filter, hence the
+// mutex.
+type Filter struct {
+	id       []byte
+	mu       sync.Mutex
+	messages *list.List
+}
+
+func newFilter(id []byte) *Filter {
+	return &Filter{id: id, messages: list.New()}
+}
+
+// GetFilterMessages returns every envelope buffered for f since the last
+// call, in arrival order.
+func GetFilterMessages(f *Filter) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	messages := make([]string, 0, f.messages.Len())
+	for e := f.messages.Front(); e != nil; e = e.Next() {
+		messages = append(messages, e.Value.(string))
+	}
+	f.messages.Init()
+	return messages
+}
+
+// registerFilter and lookupFilter hand udata to cgo via
+// github.com/mattn/go-pointer instead of a raw *int: gopointer.Save keys the
+// Filter by a genuine C-allocated pointer (not a fabricated integer cast to
+// unsafe.Pointer, which unsafe.Pointer's rules don't permit), so udata can
+// safely carry a Go *Filter across the cgo boundary and two filters can never
+// collide the way a shared *int would.
+func registerFilter(f *Filter) unsafe.Pointer {
+	return gopointer.Save(f)
+}
+
+func lookupFilter(udata unsafe.Pointer) (*Filter, bool) {
+	f, ok := gopointer.Restore(udata).(*Filter)
+	return f, ok
+}
+
+func unregisterFilter(udata unsafe.Pointer) {
+	gopointer.Unref(udata)
+}
+
 //export receiveHandler
 func receiveHandler(msg *C.received_message, udata unsafe.Pointer) {
+	filter, ok := lookupFilter(udata)
+	if !ok {
+		// The handle outlived its Filter (or never had one); nothing to do.
+		return
+	}
+
 	receivedMsg := C.GoBytes(unsafe.Pointer(msg.decoded), C.int(msg.decodedLen))
-	fmt.Printf("[nim-status] received message %s\n", string(receivedMsg))
+	text := string(receivedMsg)
 	if msg.source != nil {
 		source := C.GoBytes(unsafe.Pointer(msg.source), 64)
-		fmt.Printf("[nim-status] source public key %x\n", string(source))
+		text = fmt.Sprintf("%s (from %x)", text, string(source))
 	}
-	msgCount := (*int)(udata)
-	*msgCount += 1
-	fmt.Printf("[nim-status] message count %d\n", *msgCount)
+
+	filter.mu.Lock()
+	filter.messages.PushBack(text)
+	filter.mu.Unlock()
 }
 
 func Start() {
@@ -85,7 +143,9 @@ func StatusListenAndPost(channel string) {
 	asymKeyIdC := (*C.uint8_t)(C.CBytes(asymKeyId))
 	defer C.free(unsafe.Pointer(asymKeyIdC))
 
-	var msgCount int = 0
+	filter := newFilter(nil)
+	udata := registerFilter(filter)
+	defer unregisterFilter(udata)
 
 	options := C.filter_options{symKeyID: symKeyIdC,
 		minPow: 0.002,
@@ -94,12 +154,13 @@ func StatusListenAndPost(channel string) {
 	tmp = C.malloc(C.size_t(C.ID_LEN))
 	if C.nimbus_subscribe_filter(&options,
 		(C.received_msg_handler)(unsafe.Pointer(C.receiveHandler_cgo)),
-		unsafe.Pointer(&msgCount), (*C.uint8_t)(tmp)) == false {
+		udata, (*C.uint8_t)(tmp)) == false {
 		C.free(unsafe.Pointer(tmp))
 		panic("Cannot subscribe filter")
 	}
 	filterId := C.GoBytes(tmp, C.ID_LEN)
 	C.free(unsafe.Pointer(tmp))
+	filter.id = filterId
 	fmt.Printf("[nim-status] filter subscribed, id: %s\n",
 		hex.EncodeToString(filterId))
 
@@ -117,14 +178,18 @@ func StatusListenAndPost(channel string) {
 		i = i + 1
 		time.Sleep(1 * time.Microsecond)
 		message := fmt.Sprintf("[\"~#c4\",[\"Message:%d\",\"text/plain\",\"~:public-group-user-message\",%d,%d,[\"^ \",\"~:chat-id\",\"%s\",\"~:text\",\"Message:%d\"]]]", i, t*100, t, channel, i)
+		for _, received := range GetFilterMessages(filter) {
+			fmt.Printf("[nim-status] received message %s\n", received)
+		}
 		if i%1000 == 0 {
-			fmt.Printf("[nim-status] posting msg number %d: %s\n", msgCount, message)
-			postMessage.payload = (*C.uint8_t)(C.CBytes([]byte(message)))
+			fmt.Printf("[nim-status] posting msg number %d: %s\n", i, message)
+			payload := (*C.uint8_t)(C.CBytes([]byte(message)))
+			postMessage.payload = payload
 			postMessage.payloadLen = (C.size_t)(len([]byte(message)))
-			defer C.free(unsafe.Pointer(postMessage.payload))
 			if C.nimbus_post(&postMessage) == false {
 				fmt.Println("[nim-status] message could not be added to queue")
 			}
+			C.free(unsafe.Pointer(payload))
 		}
 	}
 }
@@ -0,0 +1,99 @@
+package main
+
+/*
+#include <stdlib.h>
+#include "libnimbus.h"
+*/
+import "C"
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+// newTestReceivedMessage builds a received_message carrying payload as its
+// decoded body, mimicking what receiveHandler_cgo hands to receiveHandler.
+func newTestReceivedMessage(payload string) *C.received_message {
+	msg := (*C.received_message)(C.malloc(C.size_t(unsafe.Sizeof(C.received_message{}))))
+	*msg = C.received_message{}
+	msg.decoded = (*C.uint8_t)(C.CBytes([]byte(payload)))
+	msg.decodedLen = C.size_t(len(payload))
+	return msg
+}
+
+func freeTestReceivedMessage(msg *C.received_message) {
+	C.free(unsafe.Pointer(msg.decoded))
+	C.free(unsafe.Pointer(msg))
+}
+
+// TestReceiveHandlerNoCrosstalk subscribes two filters and delivers envelopes
+// to both concurrently, verifying each filter only ever sees its own
+// messages even though both share the same receiveHandler entry point.
+func TestReceiveHandlerNoCrosstalk(t *testing.T) {
+	filterA := newFilter(nil)
+	udataA := registerFilter(filterA)
+	defer unregisterFilter(udataA)
+
+	filterB := newFilter(nil)
+	udataB := registerFilter(filterB)
+	defer unregisterFilter(udataB)
+
+	const messagesPerFilter = 200
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	deliver := func(udata unsafe.Pointer, prefix string) {
+		defer wg.Done()
+		for i := 0; i < messagesPerFilter; i++ {
+			msg := newTestReceivedMessage(fmt.Sprintf("%s-%d", prefix, i))
+			receiveHandler(msg, udata)
+			freeTestReceivedMessage(msg)
+		}
+	}
+
+	go deliver(udataA, "A")
+	go deliver(udataB, "B")
+	wg.Wait()
+
+	gotA := GetFilterMessages(filterA)
+	gotB := GetFilterMessages(filterB)
+
+	if len(gotA) != messagesPerFilter {
+		t.Fatalf("filter A: got %d messages, want %d", len(gotA), messagesPerFilter)
+	}
+	if len(gotB) != messagesPerFilter {
+		t.Fatalf("filter B: got %d messages, want %d", len(gotB), messagesPerFilter)
+	}
+	for _, m := range gotA {
+		if !strings.HasPrefix(m, "A-") {
+			t.Fatalf("filter A received a message meant for another filter: %q", m)
+		}
+	}
+	for _, m := range gotB {
+		if !strings.HasPrefix(m, "B-") {
+			t.Fatalf("filter B received a message meant for another filter: %q", m)
+		}
+	}
+}
+
+// TestGetFilterMessagesDrains verifies GetFilterMessages empties the buffer
+// it returns, so repeated polling doesn't redeliver old envelopes.
+func TestGetFilterMessagesDrains(t *testing.T) {
+	filter := newFilter(nil)
+	udata := registerFilter(filter)
+	defer unregisterFilter(udata)
+
+	msg := newTestReceivedMessage("hello")
+	receiveHandler(msg, udata)
+	freeTestReceivedMessage(msg)
+
+	if got := GetFilterMessages(filter); len(got) != 1 {
+		t.Fatalf("got %d messages, want 1", len(got))
+	}
+	if got := GetFilterMessages(filter); len(got) != 0 {
+		t.Fatalf("got %d messages after draining, want 0", len(got))
+	}
+}